@@ -0,0 +1,376 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect generates the SQL statements needed to manage the migrations
+// table for a particular database engine. Implementations hold no state
+// beyond what's needed to render a statement for a given table name.
+type Dialect interface {
+	// CreateTable returns the DDL that creates the migrations table if it
+	// doesn't already exist.
+	CreateTable(table string) string
+	// InsertVersion returns the DML that records a migration as applied.
+	InsertVersion(table string) string
+	// DeleteVersion returns the DML that removes a migration's record.
+	DeleteVersion(table string) string
+	// ListVersions returns the query that lists applied versions in
+	// ascending order. Integer versions sort numerically regardless of
+	// digit count (so "2" comes before "10"); version is stored as text,
+	// so this takes a dialect-specific expression rather than a plain
+	// ORDER BY version.
+	ListVersions(table string) string
+	// ListVersionsDetailed returns the query that lists applied versions,
+	// descriptions and applied_at timestamps in the same order as
+	// ListVersions.
+	ListVersionsDetailed(table string) string
+	// CurrentVersionQuery returns the query that selects the single
+	// highest applied version, using the same ordering as ListVersions.
+	CurrentVersionQuery(table string) string
+	// WidenVersionColumn returns a statement that widens an existing
+	// integer version column to text, for databases created before
+	// migrations switched to MigrationVersion. It returns "" if the
+	// dialect doesn't enforce column types and so has nothing to widen.
+	WidenVersionColumn(table string) string
+	// AddAppliedAtColumn returns a statement that adds the applied_at
+	// column, for tables created before Status and Redo needed it. Only the
+	// Postgres and ClickHouse statements use IF NOT EXISTS; the others fail
+	// against a table that already has the column.
+	AddAppliedAtColumn(table string) string
+	// Lock returns the statement and args that acquire a process-wide,
+	// session-scoped advisory lock associated with table, so two
+	// processes can't race to apply the same migration. It must run on
+	// the same connection as Unlock and the migration transaction itself.
+	// Returns "" if the dialect has no locking story.
+	Lock(table string) (query string, args []any)
+	// Unlock returns the statement and args that release the lock
+	// acquired by Lock. Returns "" if the dialect has no locking story.
+	Unlock(table string) (query string, args []any)
+}
+
+// dialectForDriver returns the Dialect that matches a database/sql driver
+// name, as passed to sql.Open.
+func dialectForDriver(driver string) (Dialect, error) {
+	switch driver {
+	case "postgres", "pgx", "postgresql":
+		return PostgresDialect{}, nil
+	case "mysql":
+		return MySQLDialect{}, nil
+	case "sqlite3", "sqlite":
+		return SQLiteDialect{}, nil
+	case "sqlserver", "mssql":
+		return SQLServerDialect{}, nil
+	case "clickhouse":
+		return ClickHouseDialect{}, nil
+	default:
+		return nil, fmt.Errorf("migrate: no dialect registered for driver %q, use NewWithDialect", driver)
+	}
+}
+
+// versionOrderWidth is how wide an integer version is zero-padded to for
+// ordering purposes. It equals the 20 digits of the largest uint64, the
+// largest integer version ParseMigrationVersion accepts.
+const versionOrderWidth = 20
+
+// postgresVersionOrder is the ORDER BY expression that sorts version
+// numerically when it holds an integer, so "10" sorts after "2" rather
+// than before it, and lexicographically otherwise (timestamp versions).
+var postgresVersionOrder = fmt.Sprintf(`CASE WHEN version ~ '^[0-9]+$' THEN lpad(version, %d, '0') ELSE version END`, versionOrderWidth)
+
+// PostgresDialect implements Dialect for PostgreSQL.
+type PostgresDialect struct{}
+
+func (PostgresDialect) CreateTable(table string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id SERIAL PRIMARY KEY,
+			version VARCHAR(255) UNIQUE NOT NULL,
+			description VARCHAR(255) UNIQUE NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT now()
+		);
+	`, table)
+}
+
+func (PostgresDialect) InsertVersion(table string) string {
+	return fmt.Sprintf("INSERT INTO %s (version, description) VALUES ($1, $2);", table)
+}
+
+func (PostgresDialect) DeleteVersion(table string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE version = $1;", table)
+}
+
+func (PostgresDialect) ListVersions(table string) string {
+	return fmt.Sprintf("SELECT version FROM %s ORDER BY %s ASC;", table, postgresVersionOrder)
+}
+
+func (PostgresDialect) ListVersionsDetailed(table string) string {
+	return fmt.Sprintf("SELECT version, description, applied_at FROM %s ORDER BY %s ASC;", table, postgresVersionOrder)
+}
+
+func (PostgresDialect) CurrentVersionQuery(table string) string {
+	return fmt.Sprintf("SELECT version FROM %s ORDER BY %s DESC LIMIT 1;", table, postgresVersionOrder)
+}
+
+func (PostgresDialect) WidenVersionColumn(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN version TYPE VARCHAR(255);", table)
+}
+
+func (PostgresDialect) AddAppliedAtColumn(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS applied_at TIMESTAMP NOT NULL DEFAULT now();", table)
+}
+
+func (PostgresDialect) Lock(table string) (string, []any) {
+	return "SELECT pg_advisory_lock($1);", []any{lockKey(table)}
+}
+
+func (PostgresDialect) Unlock(table string) (string, []any) {
+	return "SELECT pg_advisory_unlock($1);", []any{lockKey(table)}
+}
+
+// mysqlVersionOrder is MySQL's equivalent of postgresVersionOrder.
+var mysqlVersionOrder = fmt.Sprintf(`CASE WHEN version REGEXP '^[0-9]+$' THEN LPAD(version, %d, '0') ELSE version END`, versionOrderWidth)
+
+// MySQLDialect implements Dialect for MySQL/MariaDB.
+type MySQLDialect struct{}
+
+func (MySQLDialect) CreateTable(table string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY AUTO_INCREMENT,
+			version VARCHAR(255) UNIQUE NOT NULL,
+			description VARCHAR(255) UNIQUE NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`, table)
+}
+
+func (MySQLDialect) InsertVersion(table string) string {
+	return fmt.Sprintf("INSERT INTO %s (version, description) VALUES (?, ?);", table)
+}
+
+func (MySQLDialect) DeleteVersion(table string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE version = ?;", table)
+}
+
+func (MySQLDialect) ListVersions(table string) string {
+	return fmt.Sprintf("SELECT version FROM %s ORDER BY %s ASC;", table, mysqlVersionOrder)
+}
+
+func (MySQLDialect) ListVersionsDetailed(table string) string {
+	return fmt.Sprintf("SELECT version, description, applied_at FROM %s ORDER BY %s ASC;", table, mysqlVersionOrder)
+}
+
+func (MySQLDialect) CurrentVersionQuery(table string) string {
+	return fmt.Sprintf("SELECT version FROM %s ORDER BY %s DESC LIMIT 1;", table, mysqlVersionOrder)
+}
+
+func (MySQLDialect) WidenVersionColumn(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN version VARCHAR(255) NOT NULL;", table)
+}
+
+func (MySQLDialect) AddAppliedAtColumn(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;", table)
+}
+
+// Lock blocks indefinitely (GET_LOCK's timeout of -1), relying on the
+// query context (see Database.WithLockTimeout) to bound the wait instead.
+func (MySQLDialect) Lock(table string) (string, []any) {
+	return "SELECT GET_LOCK(?, -1);", []any{"migrate:" + table}
+}
+
+func (MySQLDialect) Unlock(table string) (string, []any) {
+	return "SELECT RELEASE_LOCK(?);", []any{"migrate:" + table}
+}
+
+// sqliteVersionOrder is SQLite's equivalent of postgresVersionOrder. SQLite
+// has no LPAD, so padding is done by concatenating a run of zeros in front
+// of version and keeping the last versionOrderWidth characters.
+var sqliteVersionOrder = fmt.Sprintf(
+	`CASE WHEN version GLOB '[0-9]*' AND version NOT GLOB '*[^0-9]*' THEN substr('%s' || version, -%d, %d) ELSE version END`,
+	strings.Repeat("0", versionOrderWidth), versionOrderWidth, versionOrderWidth,
+)
+
+// SQLiteDialect implements Dialect for SQLite.
+type SQLiteDialect struct{}
+
+func (SQLiteDialect) CreateTable(table string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			version VARCHAR(255) UNIQUE NOT NULL,
+			description VARCHAR(255) UNIQUE NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`, table)
+}
+
+func (SQLiteDialect) InsertVersion(table string) string {
+	return fmt.Sprintf("INSERT INTO %s (version, description) VALUES (?, ?);", table)
+}
+
+func (SQLiteDialect) DeleteVersion(table string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE version = ?;", table)
+}
+
+func (SQLiteDialect) ListVersions(table string) string {
+	return fmt.Sprintf("SELECT version FROM %s ORDER BY %s ASC;", table, sqliteVersionOrder)
+}
+
+func (SQLiteDialect) ListVersionsDetailed(table string) string {
+	return fmt.Sprintf("SELECT version, description, applied_at FROM %s ORDER BY %s ASC;", table, sqliteVersionOrder)
+}
+
+func (SQLiteDialect) CurrentVersionQuery(table string) string {
+	return fmt.Sprintf("SELECT version FROM %s ORDER BY %s DESC LIMIT 1;", table, sqliteVersionOrder)
+}
+
+// WidenVersionColumn is a no-op for SQLite: columns are dynamically typed,
+// so an existing integer version column already accepts text versions.
+func (SQLiteDialect) WidenVersionColumn(table string) string {
+	return ""
+}
+
+func (SQLiteDialect) AddAppliedAtColumn(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;", table)
+}
+
+// Lock uses a sentinel row rather than pg/MySQL-style advisory locks,
+// since SQLite has no such mechanism: the insert succeeds for exactly one
+// caller, and SQLite's own file locking serializes concurrent writers to
+// the lock table. The caller is expected to retry on failure.
+func (SQLiteDialect) Lock(table string) (string, []any) {
+	lockTable := table + "_lock"
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY CHECK (id = 1));
+		INSERT INTO %s (id) VALUES (1);
+	`, lockTable, lockTable), nil
+}
+
+func (SQLiteDialect) Unlock(table string) (string, []any) {
+	return fmt.Sprintf("DELETE FROM %s_lock WHERE id = 1;", table), nil
+}
+
+// sqlserverVersionOrder is SQL Server's equivalent of postgresVersionOrder.
+// SQL Server has no regex operator, so the numeric check uses a negated
+// LIKE character class instead.
+var sqlserverVersionOrder = fmt.Sprintf(
+	`CASE WHEN version NOT LIKE '%%[^0-9]%%' AND LEN(version) > 0 THEN RIGHT(REPLICATE('0', %d) + version, %d) ELSE version END`,
+	versionOrderWidth, versionOrderWidth,
+)
+
+// SQLServerDialect implements Dialect for Microsoft SQL Server.
+type SQLServerDialect struct{}
+
+func (SQLServerDialect) CreateTable(table string) string {
+	return fmt.Sprintf(`
+		IF NOT EXISTS (SELECT * FROM sysobjects WHERE name='%s' AND xtype='U')
+		CREATE TABLE %s (
+			id INTEGER IDENTITY(1,1) PRIMARY KEY,
+			version VARCHAR(255) UNIQUE NOT NULL,
+			description VARCHAR(255) UNIQUE NOT NULL,
+			applied_at DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME()
+		);
+	`, table, table)
+}
+
+func (SQLServerDialect) InsertVersion(table string) string {
+	return fmt.Sprintf("INSERT INTO %s (version, description) VALUES (@p1, @p2);", table)
+}
+
+func (SQLServerDialect) DeleteVersion(table string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE version = @p1;", table)
+}
+
+func (SQLServerDialect) ListVersions(table string) string {
+	return fmt.Sprintf("SELECT version FROM %s ORDER BY %s ASC;", table, sqlserverVersionOrder)
+}
+
+func (SQLServerDialect) ListVersionsDetailed(table string) string {
+	return fmt.Sprintf("SELECT version, description, applied_at FROM %s ORDER BY %s ASC;", table, sqlserverVersionOrder)
+}
+
+func (SQLServerDialect) CurrentVersionQuery(table string) string {
+	return fmt.Sprintf("SELECT TOP 1 version FROM %s ORDER BY %s DESC;", table, sqlserverVersionOrder)
+}
+
+func (SQLServerDialect) WidenVersionColumn(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN version VARCHAR(255) NOT NULL;", table)
+}
+
+func (SQLServerDialect) AddAppliedAtColumn(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD applied_at DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME();", table)
+}
+
+// Lock waits indefinitely (@LockTimeout = -1), relying on the query
+// context (see Database.WithLockTimeout) to bound the wait instead.
+func (SQLServerDialect) Lock(table string) (string, []any) {
+	return `
+		DECLARE @res INT;
+		EXEC @res = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = -1;
+		IF @res < 0 THROW 50000, 'migrate: failed to acquire migration lock', 1;
+	`, []any{"migrate:" + table}
+}
+
+func (SQLServerDialect) Unlock(table string) (string, []any) {
+	return "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session';", []any{"migrate:" + table}
+}
+
+// clickhouseVersionOrder is ClickHouse's equivalent of postgresVersionOrder.
+var clickhouseVersionOrder = fmt.Sprintf(
+	`CASE WHEN match(version, '^[0-9]+$') THEN leftPad(version, %d, '0') ELSE version END`,
+	versionOrderWidth,
+)
+
+// ClickHouseDialect implements Dialect for ClickHouse.
+type ClickHouseDialect struct{}
+
+func (ClickHouseDialect) CreateTable(table string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version String,
+			description String,
+			applied_at DateTime DEFAULT now()
+		) ENGINE = MergeTree() ORDER BY version;
+	`, table)
+}
+
+func (ClickHouseDialect) InsertVersion(table string) string {
+	return fmt.Sprintf("INSERT INTO %s (version, description) VALUES (?, ?);", table)
+}
+
+func (ClickHouseDialect) DeleteVersion(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s DELETE WHERE version = ?;", table)
+}
+
+func (ClickHouseDialect) ListVersions(table string) string {
+	return fmt.Sprintf("SELECT version FROM %s ORDER BY %s ASC;", table, clickhouseVersionOrder)
+}
+
+func (ClickHouseDialect) ListVersionsDetailed(table string) string {
+	return fmt.Sprintf("SELECT version, description, applied_at FROM %s ORDER BY %s ASC;", table, clickhouseVersionOrder)
+}
+
+func (ClickHouseDialect) CurrentVersionQuery(table string) string {
+	return fmt.Sprintf("SELECT version FROM %s ORDER BY %s DESC LIMIT 1;", table, clickhouseVersionOrder)
+}
+
+func (ClickHouseDialect) WidenVersionColumn(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN version String;", table)
+}
+
+func (ClickHouseDialect) AddAppliedAtColumn(table string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS applied_at DateTime DEFAULT now();", table)
+}
+
+// Lock is a no-op: ClickHouse has no advisory locking primitive, and its
+// MergeTree tables aren't meant for the kind of concurrent writers this
+// guards against.
+func (ClickHouseDialect) Lock(table string) (string, []any) {
+	return "", nil
+}
+
+func (ClickHouseDialect) Unlock(table string) (string, []any) {
+	return "", nil
+}
@@ -0,0 +1,107 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDialectForDriver(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   Dialect
+	}{
+		{"postgres", PostgresDialect{}},
+		{"pgx", PostgresDialect{}},
+		{"postgresql", PostgresDialect{}},
+		{"mysql", MySQLDialect{}},
+		{"sqlite3", SQLiteDialect{}},
+		{"sqlite", SQLiteDialect{}},
+		{"sqlserver", SQLServerDialect{}},
+		{"mssql", SQLServerDialect{}},
+		{"clickhouse", ClickHouseDialect{}},
+	}
+
+	for _, tt := range tests {
+		got, err := dialectForDriver(tt.driver)
+		if err != nil {
+			t.Errorf("dialectForDriver(%q) returned unexpected error: %v", tt.driver, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("dialectForDriver(%q) = %#v, want %#v", tt.driver, got, tt.want)
+		}
+	}
+
+	if _, err := dialectForDriver("unknown"); err == nil {
+		t.Error("dialectForDriver(\"unknown\") expected an error, got none")
+	}
+}
+
+// TestDialectStatementsReferenceTable checks that the migrations-table
+// statements a Dialect generates actually mention the table name they were
+// given, across every dialect this package ships. WidenVersionColumn, Lock
+// and Unlock are not checked.
+func TestDialectStatementsReferenceTable(t *testing.T) {
+	const table = "schema_migrations"
+
+	dialects := []Dialect{
+		PostgresDialect{},
+		MySQLDialect{},
+		SQLiteDialect{},
+		SQLServerDialect{},
+		ClickHouseDialect{},
+	}
+
+	for _, d := range dialects {
+		stmts := map[string]string{
+			"CreateTable":          d.CreateTable(table),
+			"InsertVersion":        d.InsertVersion(table),
+			"DeleteVersion":        d.DeleteVersion(table),
+			"ListVersions":         d.ListVersions(table),
+			"ListVersionsDetailed": d.ListVersionsDetailed(table),
+			"CurrentVersionQuery":  d.CurrentVersionQuery(table),
+			"AddAppliedAtColumn":   d.AddAppliedAtColumn(table),
+		}
+		for name, stmt := range stmts {
+			if !strings.Contains(stmt, table) {
+				t.Errorf("%T.%s(%q) = %q, want it to reference the table name", d, name, table, stmt)
+			}
+		}
+	}
+}
+
+// TestDialectListVersionsOrdersNumerically guards against the bug where
+// text ORDER BY sorted "10" before "2": every dialect's ListVersions
+// query must order integer versions by a numeric-aware expression, not
+// a bare "ORDER BY version".
+func TestDialectListVersionsOrdersNumerically(t *testing.T) {
+	dialects := []Dialect{
+		PostgresDialect{},
+		MySQLDialect{},
+		SQLiteDialect{},
+		SQLServerDialect{},
+		ClickHouseDialect{},
+	}
+
+	for _, d := range dialects {
+		for _, stmt := range []string{d.ListVersions("t"), d.ListVersionsDetailed("t"), d.CurrentVersionQuery("t")} {
+			if strings.Contains(stmt, "ORDER BY version ASC") || strings.Contains(stmt, "ORDER BY version DESC") {
+				t.Errorf("%T generated a bare text ORDER BY: %q", d, stmt)
+			}
+		}
+	}
+}
+
+func TestClickHouseLockNoopAndSQLiteLockSentinel(t *testing.T) {
+	if q, args := (ClickHouseDialect{}).Lock("t"); q != "" || args != nil {
+		t.Errorf("ClickHouseDialect.Lock should be a no-op, got (%q, %v)", q, args)
+	}
+	if q, args := (ClickHouseDialect{}).Unlock("t"); q != "" || args != nil {
+		t.Errorf("ClickHouseDialect.Unlock should be a no-op, got (%q, %v)", q, args)
+	}
+
+	q, _ := (SQLiteDialect{}).Lock("t")
+	if !strings.Contains(q, "t_lock") {
+		t.Errorf("SQLiteDialect.Lock should target the sentinel table, got %q", q)
+	}
+}
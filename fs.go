@@ -0,0 +1,183 @@
+package migrate
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// migrationFileRe matches migration file names of the form
+// NNN_description.up.sql or NNN_description.down.sql.
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// defaultStatementSeparator is the line suffix that marks the end of a
+// statement when splitting a migration file's contents.
+const defaultStatementSeparator = ";"
+
+const (
+	statementBeginMarker = "-- +migrate StatementBegin"
+	statementEndMarker   = "-- +migrate StatementEnd"
+)
+
+// FSOption configures FromFS.
+type FSOption func(*fsConfig)
+
+type fsConfig struct {
+	separator string
+}
+
+// WithStatementSeparator overrides the line suffix FromFS splits statements
+// on (default ";"). Lines between a "-- +migrate StatementBegin" and
+// "-- +migrate StatementEnd" marker are always kept as a single statement,
+// regardless of the separator, so triggers and functions containing the
+// separator still execute correctly.
+func WithStatementSeparator(separator string) FSOption {
+	return func(c *fsConfig) {
+		c.separator = separator
+	}
+}
+
+// FromFS loads migrations from dir within fsys, which must contain paired
+// NNN_description.up.sql / NNN_description.down.sql files. NNN is parsed as
+// the migration's Version and description becomes its Description. Each
+// file's contents are split into statements and executed in order against
+// the transaction passed to Up/Down.
+func FromFS(fsys fs.FS, dir string, opts ...FSOption) (Migrations, error) {
+	cfg := fsConfig{separator: defaultStatementSeparator}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type pair struct {
+		version     MigrationVersion
+		description string
+		up, down    string
+	}
+	pairs := make(map[MigrationVersion]*pair)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := ParseMigrationVersion(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version in %q: %w", entry.Name(), err)
+		}
+
+		contents, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		p, ok := pairs[version]
+		if !ok {
+			p = &pair{version: version, description: match[2]}
+			pairs[version] = p
+		}
+
+		switch match[3] {
+		case "up":
+			p.up = string(contents)
+		case "down":
+			p.down = string(contents)
+		}
+	}
+
+	versions := make([]MigrationVersion, 0, len(pairs))
+	for v := range pairs {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Compare(versions[j]) < 0 })
+
+	migrations := make(Migrations, 0, len(pairs))
+	for _, v := range versions {
+		p := pairs[v]
+		if p.up == "" || p.down == "" {
+			return nil, fmt.Errorf("migrate: migration %s (%s) is missing its up or down file", p.version, p.description)
+		}
+
+		migrations = append(migrations, Migration{
+			Version:     p.version,
+			Description: p.description,
+			Up:          execStatements(p.up, cfg.separator),
+			Down:        execStatements(p.down, cfg.separator),
+		})
+	}
+
+	return migrations, nil
+}
+
+// execStatements returns a migration function that splits body into
+// statements on separator and executes each one against tx in order.
+func execStatements(body, separator string) func(tx *sql.Tx) error {
+	statements := splitStatements(body, separator)
+	return func(tx *sql.Tx) error {
+		for _, stmt := range statements {
+			stmt = strings.TrimSpace(stmt)
+			if stmt == "" {
+				continue
+			}
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// splitStatements splits body into individual SQL statements on lines
+// ending with separator, except for lines enclosed in a
+// "-- +migrate StatementBegin" / "-- +migrate StatementEnd" block, which
+// are kept together as a single statement.
+func splitStatements(body, separator string) []string {
+	var statements []string
+	var current strings.Builder
+	inBlock := false
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch trimmed {
+		case statementBeginMarker:
+			inBlock = true
+			continue
+		case statementEndMarker:
+			inBlock = false
+			statements = append(statements, current.String())
+			current.Reset()
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if !inBlock && strings.HasSuffix(trimmed, separator) {
+			statements = append(statements, current.String())
+			current.Reset()
+		}
+	}
+
+	if strings.TrimSpace(current.String()) != "" {
+		statements = append(statements, current.String())
+	}
+
+	return statements
+}
@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		separator string
+		want      []string
+	}{
+		{
+			name:      "plain statements",
+			separator: ";",
+			body: "CREATE TABLE foo (id INT);\n" +
+				"CREATE TABLE bar (id INT);\n",
+			want: []string{
+				"CREATE TABLE foo (id INT);\n",
+				"CREATE TABLE bar (id INT);\n",
+			},
+		},
+		{
+			name:      "statement block kept together regardless of embedded separators",
+			separator: ";",
+			body: "-- +migrate StatementBegin\n" +
+				"CREATE FUNCTION f() RETURNS int AS $$\n" +
+				"BEGIN\n" +
+				"  RETURN 1;\n" +
+				"END;\n" +
+				"$$ LANGUAGE plpgsql;\n" +
+				"-- +migrate StatementEnd\n" +
+				"CREATE TABLE baz (id INT);\n",
+			want: []string{
+				"CREATE FUNCTION f() RETURNS int AS $$\nBEGIN\n  RETURN 1;\nEND;\n$$ LANGUAGE plpgsql;\n",
+				"CREATE TABLE baz (id INT);\n",
+			},
+		},
+		{
+			name:      "custom separator",
+			separator: "GO",
+			body: "CREATE TABLE foo (id INT)\nGO\n" +
+				"CREATE TABLE bar (id INT)\nGO\n",
+			want: []string{
+				"CREATE TABLE foo (id INT)\nGO\n",
+				"CREATE TABLE bar (id INT)\nGO\n",
+			},
+		},
+		{
+			name:      "trailing statement without separator is still included",
+			separator: ";",
+			body:      "CREATE TABLE foo (id INT);\nSELECT 1",
+			want: []string{
+				"CREATE TABLE foo (id INT);\n",
+				"SELECT 1\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitStatements(tt.body, tt.separator)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q, %q) = %#v, want %#v", tt.body, tt.separator, got, tt.want)
+			}
+		})
+	}
+}
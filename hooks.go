@@ -0,0 +1,78 @@
+package migrate
+
+import "log"
+
+// Logger is the minimal logging interface Database uses to report
+// migration progress, so callers can route it into slog, zap, or
+// whatever else their application already logs with.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// stdLogger routes to the standard library's log package, matching
+// migrate's historical behavior.
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, args ...any) {
+	log.Printf(format, args...)
+}
+
+// SetLogger overrides the Logger used to report migration progress. The
+// default logs through the standard library's log package.
+func (db *Database) SetLogger(logger Logger) *Database {
+	db.logger = logger
+	return db
+}
+
+// OnBeforeUp registers a callback invoked immediately before each
+// migration is applied by MigrateUp or MigrateTo.
+func (db *Database) OnBeforeUp(fn func(Migration)) *Database {
+	db.beforeUp = fn
+	return db
+}
+
+// OnAfterUp registers a callback invoked immediately after each migration
+// is applied by MigrateUp or MigrateTo, whether or not it succeeded. A
+// non-nil err means the migration (or recording it as applied) failed.
+func (db *Database) OnAfterUp(fn func(Migration, error)) *Database {
+	db.afterUp = fn
+	return db
+}
+
+// OnBeforeDown registers a callback invoked immediately before each
+// migration is rolled back by MigrateDown, MigrateTo, Redo, or Reset.
+func (db *Database) OnBeforeDown(fn func(Migration)) *Database {
+	db.beforeDown = fn
+	return db
+}
+
+// OnAfterDown registers a callback invoked immediately after each
+// migration is rolled back, whether or not it succeeded.
+func (db *Database) OnAfterDown(fn func(Migration, error)) *Database {
+	db.afterDown = fn
+	return db
+}
+
+func (db *Database) callBeforeUp(m Migration) {
+	if db.beforeUp != nil {
+		db.beforeUp(m)
+	}
+}
+
+func (db *Database) callAfterUp(m Migration, err error) {
+	if db.afterUp != nil {
+		db.afterUp(m, err)
+	}
+}
+
+func (db *Database) callBeforeDown(m Migration) {
+	if db.beforeDown != nil {
+		db.beforeDown(m)
+	}
+}
+
+func (db *Database) callAfterDown(m Migration, err error) {
+	if db.afterDown != nil {
+		db.afterDown(m, err)
+	}
+}
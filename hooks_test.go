@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHookCallbacksNilSafe(t *testing.T) {
+	db := &Database{}
+	m := Migration{Version: "1", Description: "first"}
+
+	// None of these should panic when no callback has been registered.
+	db.callBeforeUp(m)
+	db.callAfterUp(m, nil)
+	db.callBeforeDown(m)
+	db.callAfterDown(m, nil)
+}
+
+func TestHookCallbacksFire(t *testing.T) {
+	db := &Database{}
+	m := Migration{Version: "1", Description: "first"}
+	wantErr := errors.New("boom")
+
+	var gotBeforeUp, gotBeforeDown Migration
+	var gotAfterUpErr, gotAfterDownErr error
+	beforeUpCalled, afterUpCalled := false, false
+	beforeDownCalled, afterDownCalled := false, false
+
+	db.OnBeforeUp(func(got Migration) {
+		beforeUpCalled = true
+		gotBeforeUp = got
+	})
+	db.OnAfterUp(func(got Migration, err error) {
+		afterUpCalled = true
+		gotAfterUpErr = err
+	})
+	db.OnBeforeDown(func(got Migration) {
+		beforeDownCalled = true
+		gotBeforeDown = got
+	})
+	db.OnAfterDown(func(got Migration, err error) {
+		afterDownCalled = true
+		gotAfterDownErr = err
+	})
+
+	db.callBeforeUp(m)
+	db.callAfterUp(m, wantErr)
+	db.callBeforeDown(m)
+	db.callAfterDown(m, wantErr)
+
+	if !beforeUpCalled || gotBeforeUp.Version != m.Version {
+		t.Error("OnBeforeUp callback was not invoked with the migration")
+	}
+	if !afterUpCalled || gotAfterUpErr != wantErr {
+		t.Error("OnAfterUp callback was not invoked with the migration's error")
+	}
+	if !beforeDownCalled || gotBeforeDown.Version != m.Version {
+		t.Error("OnBeforeDown callback was not invoked with the migration")
+	}
+	if !afterDownCalled || gotAfterDownErr != wantErr {
+		t.Error("OnAfterDown callback was not invoked with the migration's error")
+	}
+}
+
+func TestOnHooksReturnDatabaseForChaining(t *testing.T) {
+	db := &Database{}
+	if got := db.OnBeforeUp(func(Migration) {}); got != db {
+		t.Error("OnBeforeUp should return the same *Database for chaining")
+	}
+	if got := db.OnAfterUp(func(Migration, error) {}); got != db {
+		t.Error("OnAfterUp should return the same *Database for chaining")
+	}
+	if got := db.OnBeforeDown(func(Migration) {}); got != db {
+		t.Error("OnBeforeDown should return the same *Database for chaining")
+	}
+	if got := db.OnAfterDown(func(Migration, error) {}); got != db {
+		t.Error("OnAfterDown should return the same *Database for chaining")
+	}
+	if got := db.SetLogger(stdLogger{}); got != db {
+		t.Error("SetLogger should return the same *Database for chaining")
+	}
+}
@@ -0,0 +1,78 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// lockPollInterval is how often Database retries acquiring a lock that
+// doesn't block natively in SQL (SQLite's sentinel row).
+const lockPollInterval = 50 * time.Millisecond
+
+// WithLockTimeout bounds how long MigrateUp and MigrateDown wait to
+// acquire the migration lock before giving up, so a deploy doesn't hang
+// forever behind a migration stuck on another instance. The zero value
+// (the default) waits indefinitely.
+func (db *Database) WithLockTimeout(d time.Duration) *Database {
+	db.lockTimeout = d
+	return db
+}
+
+// lockKey derives a stable int64 key from table, for dialects (Postgres)
+// whose advisory locking functions take a number rather than a name.
+func lockKey(table string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(table))
+	return int64(h.Sum64())
+}
+
+// acquireLock takes the dialect's advisory lock for the migrations table
+// over conn, so two processes can't race to apply the same migration. It
+// blocks until the lock is acquired or db.lockTimeout (if set) elapses.
+// Dialects with no locking story (ClickHouse) return an empty query from
+// Lock and this is a no-op.
+func (db *Database) acquireLock(ctx context.Context, conn *sql.Conn) error {
+	query, args := db.dialect.Lock(db.migrationTable)
+	if query == "" {
+		return nil
+	}
+
+	lockCtx := ctx
+	if db.lockTimeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, db.lockTimeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := conn.ExecContext(lockCtx, query, args...)
+		if err == nil {
+			return nil
+		}
+		if lockCtx.Err() != nil {
+			return fmt.Errorf("migrate: timed out waiting for migration lock: %w", err)
+		}
+
+		select {
+		case <-lockCtx.Done():
+			return fmt.Errorf("migrate: timed out waiting for migration lock: %w", lockCtx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// releaseLock releases the lock taken by acquireLock.
+func (db *Database) releaseLock(ctx context.Context, conn *sql.Conn) error {
+	query, args := db.dialect.Unlock(db.migrationTable)
+	if query == "" {
+		return nil
+	}
+	_, err := conn.ExecContext(ctx, query, args...)
+	return err
+}
@@ -0,0 +1,30 @@
+package migrate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockKeyIsStableAndDistinct(t *testing.T) {
+	a1 := lockKey("migrations")
+	a2 := lockKey("migrations")
+	if a1 != a2 {
+		t.Errorf("lockKey(%q) is not stable: got %d and %d", "migrations", a1, a2)
+	}
+
+	b := lockKey("other_migrations")
+	if a1 == b {
+		t.Errorf("lockKey returned the same key for different tables: %d", a1)
+	}
+}
+
+func TestWithLockTimeout(t *testing.T) {
+	db := &Database{}
+
+	if got := db.WithLockTimeout(5 * time.Second); got != db {
+		t.Error("WithLockTimeout should return the same *Database for chaining")
+	}
+	if db.lockTimeout != 5*time.Second {
+		t.Errorf("lockTimeout = %v, want %v", db.lockTimeout, 5*time.Second)
+	}
+}
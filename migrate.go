@@ -4,32 +4,42 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"sort"
+	"time"
 
 	"golang.org/x/exp/slices"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // Migration represents a database migration with a version, description, up and down functions.
 type Migration struct {
-	Version     uint
+	Version     MigrationVersion
 	Description string
 	Up          func(tx *sql.Tx) error
 	Down        func(tx *sql.Tx) error
+
+	// NoTx marks a migration whose DDL can't run inside a transaction
+	// (e.g. Postgres' CREATE INDEX CONCURRENTLY, many MySQL online DDL
+	// statements). When set, UpNoTx/DownNoTx are used instead of Up/Down:
+	// the runner commits progress made so far, runs the migration against
+	// the raw connection, then records it as applied in its own short
+	// transaction. This means a NoTx migration that fails partway through
+	// can leave the schema changed but unrecorded, unlike transactional
+	// migrations, which roll back cleanly.
+	NoTx     bool
+	UpNoTx   func(conn *sql.DB) error
+	DownNoTx func(conn *sql.DB) error
 }
 
 // Migrations is a slice of Migration.
 type Migrations []Migration
 
 // Sorted returns a sorted slice of migrations based on their versions.
-func (ms *Migrations) sorted() []Migration {
+func (ms *Migrations) Sorted() []Migration {
 	sortedMigrations := make([]Migration, len(*ms))
 	copy(sortedMigrations, *ms)
 
 	sort.Slice(sortedMigrations, func(i, j int) bool {
-		return sortedMigrations[i].Version < sortedMigrations[j].Version
+		return sortedMigrations[i].Version.Compare(sortedMigrations[j].Version) < 0
 	})
 	return sortedMigrations
 }
@@ -37,30 +47,62 @@ func (ms *Migrations) sorted() []Migration {
 // Database represents a database connection and migration data.
 type Database struct {
 	conn           *sql.DB
+	dialect        Dialect
 	migrationTable string
 	migrations     *Migrations
+	lockTimeout    time.Duration
+	logger         Logger
+
+	beforeUp   func(Migration)
+	afterUp    func(Migration, error)
+	beforeDown func(Migration)
+	afterDown  func(Migration, error)
 }
 
-// New creates a new database instance with a DSN string and migrations.
-func New(dsn string, migrations *Migrations) (*Database, error) {
-	conn, err := sql.Open("sqlite3", dsn)
+// New creates a new database instance for the given driver and DSN string.
+// The driver name is also used to resolve the Dialect, so it must be one
+// that migrate knows about (see dialectForDriver); callers using a driver
+// migrate doesn't recognize should use NewWithDialect instead. The driver's
+// package must be imported by the caller, since migrate no longer imports
+// any database/sql drivers itself.
+func New(driver, dsn string, migrations *Migrations) (*Database, error) {
+	dialect, err := dialectForDriver(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Database{
 		conn:           conn,
+		dialect:        dialect,
 		migrationTable: "migrations",
 		migrations:     migrations,
+		logger:         stdLogger{},
 	}, nil
 }
 
 // NewWithConn creates a new database instance with a database connection and migrations.
+// The SQLite dialect is used for compatibility with existing callers; use
+// NewWithDialect to target a different database engine.
 func NewWithConn(conn *sql.DB, migrations *Migrations) *Database {
+	return NewWithDialect(conn, SQLiteDialect{}, migrations)
+}
+
+// NewWithDialect creates a new database instance with a connection, an
+// explicit Dialect, and migrations. Use this when the driver you're
+// connecting with isn't one New recognizes, or when you want full control
+// over which SQL dialect is used.
+func NewWithDialect(conn *sql.DB, dialect Dialect, migrations *Migrations) *Database {
 	return &Database{
 		conn:           conn,
+		dialect:        dialect,
 		migrationTable: "migrations",
 		migrations:     migrations,
+		logger:         stdLogger{},
 	}
 }
 
@@ -77,11 +119,28 @@ func (db *Database) SetMigrationTable(table string) *Database {
 
 // MigrateUp migrates the database up to the current version (highest version).
 func (db *Database) MigrateUp(ctx context.Context) error {
-	tx, err := db.conn.BeginTx(ctx, nil)
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := db.acquireLock(ctx, conn); err != nil {
+		return err
+	}
+	defer db.releaseLock(ctx, conn)
+
+	var tx *sql.Tx
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+
+	tx, err = conn.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
 	err = db.createMigrationTable(ctx, tx)
 	if err != nil {
@@ -93,40 +152,96 @@ func (db *Database) MigrateUp(ctx context.Context) error {
 		return err
 	}
 
-	for _, migration := range db.migrations.sorted() {
-		if migration.Version == 0 || migration.Description == "" {
+	for _, migration := range db.migrations.Sorted() {
+		if migration.Version == "" || migration.Description == "" {
 			return fmt.Errorf("invalid migration: version and description must be set")
 		}
 
-		if migration.Up == nil || migration.Down == nil {
+		if migration.NoTx {
+			if migration.UpNoTx == nil || migration.DownNoTx == nil {
+				return fmt.Errorf("invalid migration: NoTx migrations must set UpNoTx and DownNoTx")
+			}
+		} else if migration.Up == nil || migration.Down == nil {
 			return fmt.Errorf("invalid migration: up and down must be set")
 		}
 
 		if slices.Contains(index, migration.Version) {
-			log.Printf("skipping migration (version=%v, description=%s) already exists", migration.Version, migration.Description)
+			db.logger.Printf("skipping migration (version=%s, description=%s) already exists", migration.Version, migration.Description)
+			continue
+		}
+
+		db.callBeforeUp(migration)
+
+		if migration.NoTx {
+			// Commit whatever's been applied so far: the DDL below can't
+			// run inside this transaction, so there's nothing left to
+			// roll it back with anyway.
+			if err := tx.Commit(); err != nil {
+				db.callAfterUp(migration, err)
+				return err
+			}
+			tx = nil
+
+			if err := migration.UpNoTx(db.conn); err != nil {
+				db.callAfterUp(migration, err)
+				return err
+			}
+
+			if err := db.insertMigrationNoTx(ctx, conn, migration.Version, migration.Description); err != nil {
+				db.callAfterUp(migration, err)
+				return err
+			}
+
+			db.callAfterUp(migration, nil)
+			db.logger.Printf("migration up (version=%s, description=%s, notx=true)", migration.Version, migration.Description)
+
+			tx, err = conn.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
 			continue
 		}
 
 		if err := migration.Up(tx); err != nil {
+			db.callAfterUp(migration, err)
 			return err
 		}
 
 		if err := db.insertMigration(ctx, tx, migration.Version, migration.Description); err != nil {
+			db.callAfterUp(migration, err)
 			return err
 		}
 
-		log.Printf("migration up (version=%v, description=%s)", migration.Version, migration.Description)
+		db.callAfterUp(migration, nil)
+		db.logger.Printf("migration up (version=%s, description=%s)", migration.Version, migration.Description)
 	}
 	return tx.Commit()
 }
 
 // MigrateDown migrates the database down by the specified amount.
 func (db *Database) MigrateDown(ctx context.Context, amount int) error {
-	tx, err := db.conn.BeginTx(ctx, nil)
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := db.acquireLock(ctx, conn); err != nil {
+		return err
+	}
+	defer db.releaseLock(ctx, conn)
+
+	var tx *sql.Tx
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+
+	tx, err = conn.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
 	err = db.createMigrationTable(ctx, tx)
 	if err != nil {
@@ -146,77 +261,125 @@ func (db *Database) MigrateDown(ctx context.Context, amount int) error {
 		amount = len(index)
 	}
 
+	byVersion := make(map[MigrationVersion]Migration, len(*db.migrations))
+	for _, migration := range *db.migrations {
+		byVersion[migration.Version] = migration
+	}
+
 	for i := len(index) - 1; i >= len(index)-amount; i-- {
-		migration := db.migrations.sorted()[index[i]-1]
+		migration, ok := byVersion[index[i]]
+		if !ok {
+			return fmt.Errorf("migration (version=%s) doesn't exist", index[i])
+		}
 
-		if migration.Version == 0 || migration.Description == "" {
+		if migration.Version == "" || migration.Description == "" {
 			return fmt.Errorf("invalid migration: version and description must be set")
 		}
 
-		if migration.Up == nil || migration.Down == nil {
+		if migration.NoTx {
+			if migration.UpNoTx == nil || migration.DownNoTx == nil {
+				return fmt.Errorf("invalid migration: NoTx migrations must set UpNoTx and DownNoTx")
+			}
+		} else if migration.Up == nil || migration.Down == nil {
 			return fmt.Errorf("invalid migration: up and down must be set")
 		}
 
-		if !slices.Contains(index, migration.Version) {
-			return fmt.Errorf("migration (version=%v, description=%s) doesn't exists", migration.Version, migration.Description)
+		db.callBeforeDown(migration)
+
+		if migration.NoTx {
+			if err := tx.Commit(); err != nil {
+				db.callAfterDown(migration, err)
+				return err
+			}
+			tx = nil
+
+			if err := migration.DownNoTx(db.conn); err != nil {
+				db.callAfterDown(migration, err)
+				return err
+			}
+
+			if err := db.deleteMigrationNoTx(ctx, conn, migration.Version); err != nil {
+				db.callAfterDown(migration, err)
+				return err
+			}
+
+			db.callAfterDown(migration, nil)
+			db.logger.Printf("migration down (version=%s, description=%s, notx=true)", migration.Version, migration.Description)
+
+			tx, err = conn.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			continue
 		}
 
 		if err := migration.Down(tx); err != nil {
+			db.callAfterDown(migration, err)
 			return err
 		}
 
 		if err := db.deleteMigration(ctx, tx, migration.Version); err != nil {
+			db.callAfterDown(migration, err)
 			return err
 		}
 
-		log.Printf("migration down (version=%v, description=%s)", migration.Version, migration.Description)
+		db.callAfterDown(migration, nil)
+		db.logger.Printf("migration down (version=%s, description=%s)", migration.Version, migration.Description)
 	}
 	return tx.Commit()
 }
 
-// CurrentVersion returns the current version of the database.
-func (db *Database) CurrentVersion(ctx context.Context) (uint, error) {
-	query := fmt.Sprintf("SELECT version FROM %s ORDER BY version DESC LIMIT 1;", db.migrationTable)
-
-	rows, err := db.conn.QueryContext(ctx, query)
+// CurrentVersion returns the highest applied version, or "" if no
+// migrations have been applied yet. Integer versions are compared
+// numerically, not lexicographically, so this is safe past version "9".
+func (db *Database) CurrentVersion(ctx context.Context) (MigrationVersion, error) {
+	rows, err := db.conn.QueryContext(ctx, db.dialect.CurrentVersionQuery(db.migrationTable))
 	if err != nil {
-		return 0, err
+		return "", err
 	}
 
 	if !rows.Next() {
-		return 0, nil
+		return "", nil
 	}
 
-	version := uint(0)
+	var version MigrationVersion
 	if err := rows.Scan(&version); err != nil {
-		return 0, err
+		return "", err
 	}
 	return version, nil
 }
 
 func (db *Database) createMigrationTable(ctx context.Context, tx *sql.Tx) error {
-	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			version INTEGER UNIQUE NOT NULL,
-			description VARCHAR(255) UNIQUE NOT NULL
-		);
-	`, db.migrationTable))
-	return err
-}
+	if _, err := tx.ExecContext(ctx, db.dialect.CreateTable(db.migrationTable)); err != nil {
+		return err
+	}
+
+	// Widen a pre-existing integer version column to text, for databases
+	// created before migrations switched to MigrationVersion. This is best
+	// effort: on a freshly created table the column is already the right
+	// type, and engines that don't enforce column types return no statement
+	// at all, so errors here are intentionally ignored rather than failing
+	// the whole migration run.
+	if stmt := db.dialect.WidenVersionColumn(db.migrationTable); stmt != "" {
+		_, _ = tx.ExecContext(ctx, stmt)
+	}
 
-func (db *Database) getMigrationIndex(ctx context.Context, tx *sql.Tx) ([]uint, error) {
-	query := fmt.Sprintf("SELECT version FROM %s ORDER BY version ASC;", db.migrationTable)
+	// Same idea for applied_at: harmless against a fresh table or one that
+	// already has the column.
+	_, _ = tx.ExecContext(ctx, db.dialect.AddAppliedAtColumn(db.migrationTable))
+	return nil
+}
 
-	rows, err := tx.QueryContext(ctx, query)
+func (db *Database) getMigrationIndex(ctx context.Context, tx *sql.Tx) ([]MigrationVersion, error) {
+	rows, err := tx.QueryContext(ctx, db.dialect.ListVersions(db.migrationTable))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	index := make([]uint, 0)
+	index := make([]MigrationVersion, 0)
 	for rows.Next() {
-		var version uint
+		var version MigrationVersion
 		if err := rows.Scan(&version); err != nil {
 			return nil, err
 		}
@@ -225,14 +388,42 @@ func (db *Database) getMigrationIndex(ctx context.Context, tx *sql.Tx) ([]uint,
 	return index, nil
 }
 
-func (db *Database) insertMigration(ctx context.Context, tx *sql.Tx, version uint, description string) error {
-	query := fmt.Sprintf("INSERT INTO %s (version, description) VALUES (?, ?);", db.migrationTable)
-	_, err := tx.ExecContext(ctx, query, version, description)
+func (db *Database) insertMigration(ctx context.Context, tx *sql.Tx, version MigrationVersion, description string) error {
+	_, err := tx.ExecContext(ctx, db.dialect.InsertVersion(db.migrationTable), version, description)
 	return err
 }
 
-func (db *Database) deleteMigration(ctx context.Context, tx *sql.Tx, version uint) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE version = ?;", db.migrationTable)
-	_, err := tx.ExecContext(ctx, query, version)
+func (db *Database) deleteMigration(ctx context.Context, tx *sql.Tx, version MigrationVersion) error {
+	_, err := tx.ExecContext(ctx, db.dialect.DeleteVersion(db.migrationTable), version)
 	return err
 }
+
+// insertMigrationNoTx records a NoTx migration as applied in its own
+// short transaction, separate from the migration itself.
+func (db *Database) insertMigrationNoTx(ctx context.Context, conn *sql.Conn, version MigrationVersion, description string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := db.insertMigration(ctx, tx, version, description); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteMigrationNoTx is the NoTx counterpart to insertMigrationNoTx, used
+// when rolling a NoTx migration back.
+func (db *Database) deleteMigrationNoTx(ctx context.Context, conn *sql.Conn, version MigrationVersion) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := db.deleteMigration(ctx, tx, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
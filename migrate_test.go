@@ -1,6 +1,7 @@
 package migrate_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/tinytoolkit/migrate"
@@ -8,18 +9,39 @@ import (
 
 func TestMigrationSorting(t *testing.T) {
 	migrations := migrate.Migrations{
-		{Version: 1, Description: "first migration"},
-		{Version: 4, Description: "fourth migration"},
-		{Version: 3, Description: "third migration"},
-		{Version: 5, Description: "fifth migration"},
-		{Version: 2, Description: "second migration"},
+		{Version: "1", Description: "first migration"},
+		{Version: "4", Description: "fourth migration"},
+		{Version: "3", Description: "third migration"},
+		{Version: "5", Description: "fifth migration"},
+		{Version: "2", Description: "second migration"},
 	}
 
 	sorted := migrations.Sorted()
 
 	for i, m := range sorted {
-		if i+1 != int(m.Version) {
-			t.Errorf("expected version %d, got %d", i+1, m.Version)
+		if fmt.Sprintf("%d", i+1) != m.Version.String() {
+			t.Errorf("expected version %d, got %s", i+1, m.Version)
+		}
+	}
+}
+
+// TestMigrationSortingDoubleDigit guards against sorting versions as plain
+// strings, which would put "10" and "11" before "2" through "9".
+func TestMigrationSortingDoubleDigit(t *testing.T) {
+	migrations := migrate.Migrations{
+		{Version: "11", Description: "eleventh migration"},
+		{Version: "2", Description: "second migration"},
+		{Version: "1", Description: "first migration"},
+		{Version: "10", Description: "tenth migration"},
+		{Version: "9", Description: "ninth migration"},
+	}
+
+	sorted := migrations.Sorted()
+
+	want := []string{"1", "2", "9", "10", "11"}
+	for i, m := range sorted {
+		if want[i] != m.Version.String() {
+			t.Errorf("position %d: expected version %s, got %s", i, want[i], m.Version)
 		}
 	}
 }
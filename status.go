@@ -0,0 +1,275 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// MigrationStatus describes whether a single migration has been applied,
+// and when.
+type MigrationStatus struct {
+	Version     MigrationVersion
+	Description string
+	Applied     bool
+	AppliedAt   *time.Time
+}
+
+// Status returns the applied/pending state of every known migration,
+// sorted by version.
+func (db *Database) Status(ctx context.Context) ([]MigrationStatus, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := db.createMigrationTable(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, db.dialect.ListVersionsDetailed(db.migrationTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[MigrationVersion]time.Time)
+	for rows.Next() {
+		var version MigrationVersion
+		var description string
+		var at time.Time
+		if err := rows.Scan(&version, &description, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return buildStatuses(db.migrations.Sorted(), appliedAt), nil
+}
+
+// buildStatuses merges the known migrations with the applied_at times read
+// from the migrations table, producing one MigrationStatus per migration in
+// sorted order.
+func buildStatuses(sorted []Migration, appliedAt map[MigrationVersion]time.Time) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(sorted))
+	for _, migration := range sorted {
+		status := MigrationStatus{
+			Version:     migration.Version,
+			Description: migration.Description,
+		}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.Applied = true
+			at := at
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// MigrateTo migrates the database up or down to reach version, applying
+// or rolling back whatever migrations lie between the current state and
+// the target. NoTx migrations are supported the same way MigrateUp and
+// MigrateDown handle them: the ambient transaction is committed first,
+// the migration runs against the raw connection, and it's recorded as
+// applied (or removed) in its own short transaction.
+func (db *Database) MigrateTo(ctx context.Context, version MigrationVersion) error {
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var tx *sql.Tx
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+
+	tx, err = conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := db.createMigrationTable(ctx, tx); err != nil {
+		return err
+	}
+
+	index, err := db.getMigrationIndex(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[MigrationVersion]bool, len(index))
+	for _, v := range index {
+		applied[v] = true
+	}
+
+	sorted := db.migrations.Sorted()
+
+	for _, migration := range sorted {
+		if applied[migration.Version] || migration.Version.Compare(version) > 0 {
+			continue
+		}
+
+		if migration.Description == "" {
+			return fmt.Errorf("invalid migration: description and up must be set")
+		}
+		if migration.NoTx {
+			if migration.UpNoTx == nil || migration.DownNoTx == nil {
+				return fmt.Errorf("invalid migration: NoTx migrations must set UpNoTx and DownNoTx")
+			}
+		} else if migration.Up == nil {
+			return fmt.Errorf("invalid migration: description and up must be set")
+		}
+
+		db.callBeforeUp(migration)
+
+		if migration.NoTx {
+			if err := tx.Commit(); err != nil {
+				db.callAfterUp(migration, err)
+				return err
+			}
+			tx = nil
+
+			if err := migration.UpNoTx(db.conn); err != nil {
+				db.callAfterUp(migration, err)
+				return err
+			}
+
+			if err := db.insertMigrationNoTx(ctx, conn, migration.Version, migration.Description); err != nil {
+				db.callAfterUp(migration, err)
+				return err
+			}
+
+			db.callAfterUp(migration, nil)
+			db.logger.Printf("migration up (version=%s, description=%s, notx=true)", migration.Version, migration.Description)
+
+			tx, err = conn.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := migration.Up(tx); err != nil {
+			db.callAfterUp(migration, err)
+			return err
+		}
+
+		if err := db.insertMigration(ctx, tx, migration.Version, migration.Description); err != nil {
+			db.callAfterUp(migration, err)
+			return err
+		}
+
+		db.callAfterUp(migration, nil)
+		db.logger.Printf("migration up (version=%s, description=%s)", migration.Version, migration.Description)
+	}
+
+	for i := len(sorted) - 1; i >= 0; i-- {
+		migration := sorted[i]
+		if !applied[migration.Version] || migration.Version.Compare(version) <= 0 {
+			continue
+		}
+
+		if migration.NoTx {
+			if migration.UpNoTx == nil || migration.DownNoTx == nil {
+				return fmt.Errorf("invalid migration: NoTx migrations must set UpNoTx and DownNoTx")
+			}
+		} else if migration.Down == nil {
+			return fmt.Errorf("invalid migration: down must be set")
+		}
+
+		db.callBeforeDown(migration)
+
+		if migration.NoTx {
+			if err := tx.Commit(); err != nil {
+				db.callAfterDown(migration, err)
+				return err
+			}
+			tx = nil
+
+			if err := migration.DownNoTx(db.conn); err != nil {
+				db.callAfterDown(migration, err)
+				return err
+			}
+
+			if err := db.deleteMigrationNoTx(ctx, conn, migration.Version); err != nil {
+				db.callAfterDown(migration, err)
+				return err
+			}
+
+			db.callAfterDown(migration, nil)
+			db.logger.Printf("migration down (version=%s, description=%s, notx=true)", migration.Version, migration.Description)
+
+			tx, err = conn.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := migration.Down(tx); err != nil {
+			db.callAfterDown(migration, err)
+			return err
+		}
+
+		if err := db.deleteMigration(ctx, tx, migration.Version); err != nil {
+			db.callAfterDown(migration, err)
+			return err
+		}
+
+		db.callAfterDown(migration, nil)
+		db.logger.Printf("migration down (version=%s, description=%s)", migration.Version, migration.Description)
+	}
+
+	return tx.Commit()
+}
+
+// Redo rolls back the applied migration with the highest version and then
+// runs MigrateUp, which re-applies it together with any other pending
+// migrations. Handy while iterating on a migration during development.
+func (db *Database) Redo(ctx context.Context) error {
+	if err := db.MigrateDown(ctx, 1); err != nil {
+		return err
+	}
+	return db.MigrateUp(ctx)
+}
+
+// Reset rolls back every applied migration.
+func (db *Database) Reset(ctx context.Context) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := db.createMigrationTable(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	index, err := db.getMigrationIndex(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(index) == 0 {
+		return nil
+	}
+	return db.MigrateDown(ctx, len(index))
+}
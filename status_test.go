@@ -0,0 +1,37 @@
+package migrate
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBuildStatuses exercises the pure merge logic behind Status. The rest
+// of Status (reading applied_at out of the migrations table) needs a live
+// database connection to test and isn't covered here.
+func TestBuildStatuses(t *testing.T) {
+	appliedAt := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	sorted := []Migration{
+		{Version: "1", Description: "first"},
+		{Version: "2", Description: "second"},
+		{Version: "3", Description: "third"},
+	}
+
+	statuses := buildStatuses(sorted, map[MigrationVersion]time.Time{
+		"1": appliedAt,
+		"3": appliedAt,
+	})
+
+	if len(statuses) != 3 {
+		t.Fatalf("got %d statuses, want 3", len(statuses))
+	}
+
+	if !statuses[0].Applied || statuses[0].AppliedAt == nil || !statuses[0].AppliedAt.Equal(appliedAt) {
+		t.Errorf("version 1: expected applied at %v, got %+v", appliedAt, statuses[0])
+	}
+	if statuses[1].Applied || statuses[1].AppliedAt != nil {
+		t.Errorf("version 2: expected pending, got %+v", statuses[1])
+	}
+	if !statuses[2].Applied || statuses[2].AppliedAt == nil || !statuses[2].AppliedAt.Equal(appliedAt) {
+		t.Errorf("version 3: expected applied at %v, got %+v", appliedAt, statuses[2])
+	}
+}
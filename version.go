@@ -0,0 +1,73 @@
+package migrate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MigrationVersion identifies a migration. It holds either a sequential
+// integer ("1", "42") or an RFC3339 timestamp ("2024-01-15T12:00:00Z"), so
+// that teams working in parallel branches can add migrations without
+// colliding on a shared counter.
+type MigrationVersion string
+
+// String returns the version's underlying text.
+func (v MigrationVersion) String() string {
+	return string(v)
+}
+
+// Compare returns -1, 0, or 1 if v is less than, equal to, or greater than
+// other. If both versions are integers, they're zero-padded to the same
+// width first so "2" sorts before "10"; otherwise they're compared as
+// plain strings, without padding. For RFC3339 timestamps that share a time
+// zone offset and fractional-second precision, that order is chronological;
+// otherwise it may not be (e.g. "...T12:00:00.5Z" sorts before
+// "...T12:00:00Z").
+func (v MigrationVersion) Compare(other MigrationVersion) int {
+	a, b := string(v), string(other)
+
+	if isIntegerVersion(a) && isIntegerVersion(b) {
+		width := len(a)
+		if len(b) > width {
+			width = len(b)
+		}
+		a = strings.Repeat("0", width-len(a)) + a
+		b = strings.Repeat("0", width-len(b)) + b
+	}
+
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// isIntegerVersion reports whether s is a sequential-integer version
+// rather than an RFC3339 timestamp version.
+func isIntegerVersion(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+// ParseMigrationVersion parses s as a MigrationVersion, accepting either a
+// sequential integer or an RFC3339 timestamp.
+func ParseMigrationVersion(s string) (MigrationVersion, error) {
+	if s == "" {
+		return "", fmt.Errorf("migrate: version must not be empty")
+	}
+
+	if _, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return MigrationVersion(s), nil
+	}
+
+	if _, err := time.Parse(time.RFC3339, s); err == nil {
+		return MigrationVersion(s), nil
+	}
+
+	return "", fmt.Errorf("migrate: version %q is neither an integer nor an RFC3339 timestamp", s)
+}
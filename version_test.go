@@ -0,0 +1,53 @@
+package migrate
+
+import "testing"
+
+func TestMigrationVersionCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b MigrationVersion
+		want int
+	}{
+		{"equal integers", "9", "9", 0},
+		{"single vs double digit integers", "2", "10", -1},
+		{"double vs single digit integers", "10", "2", 1},
+		{"equal width integers", "10", "11", -1},
+		{
+			name: "timestamps without and with fractional seconds",
+			a:    "2024-01-15T12:00:00Z",
+			b:    "2023-12-31T23:59:59.999999Z",
+			want: 1,
+		},
+		{
+			name: "timestamps with fractional seconds reversed",
+			a:    "2023-12-31T23:59:59.999999Z",
+			b:    "2024-01-15T12:00:00Z",
+			want: -1,
+		},
+		{"equal timestamps", "2024-01-15T12:00:00Z", "2024-01-15T12:00:00Z", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Compare(tt.b); got != tt.want {
+				t.Errorf("%q.Compare(%q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMigrationVersion(t *testing.T) {
+	valid := []string{"0", "1", "42", "2024-01-15T12:00:00Z", "2023-12-31T23:59:59.999999Z"}
+	for _, s := range valid {
+		if _, err := ParseMigrationVersion(s); err != nil {
+			t.Errorf("ParseMigrationVersion(%q) returned unexpected error: %v", s, err)
+		}
+	}
+
+	invalid := []string{"", "not-a-version", "1.5", "2024-13-99"}
+	for _, s := range invalid {
+		if _, err := ParseMigrationVersion(s); err == nil {
+			t.Errorf("ParseMigrationVersion(%q) expected an error, got none", s)
+		}
+	}
+}